@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// configManager owns the currently-applied Config, watches the config file
+// for changes, and keeps the scraper subsystem and exported metrics in sync
+// with it.
+type configManager struct {
+	mu      sync.Mutex
+	path    string
+	current Config
+	scraper *scraperManager
+}
+
+func newConfigManager(path string, config Config, scraper *scraperManager) *configManager {
+	return &configManager{path: path, current: config, scraper: scraper}
+}
+
+// snapshot returns the currently-applied Config, safe for concurrent use
+// with reload.
+func (cm *configManager) snapshot() Config {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	return cm.current
+}
+
+// watch blocks, reloading the config on every write/create event for
+// cm.path. Watcher errors are logged, not fatal, mirroring the non-fatal
+// reload behaviour below.
+//
+// It watches cm.path's parent directory rather than the file itself: per
+// fsnotify's documented caveats, watching a file directly breaks the moment
+// that file is replaced via atomic rename, which is how many editors save.
+// Watching the directory and filtering by name survives those rewrites.
+// Events for other names in the directory are ignored, so a Kubernetes
+// ConfigMap update, which swaps the ..data symlink rather than cm.path
+// itself, does not trigger a reload.
+func (cm *configManager) watch() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("Error creating config watcher: %v", err)
+		return
+	}
+	defer watcher.Close()
+
+	dir := filepath.Dir(cm.path)
+	if err := watcher.Add(dir); err != nil {
+		log.Printf("Error watching %s: %v", dir, err)
+		return
+	}
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != filepath.Clean(cm.path) {
+				continue
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			if err := cm.reload(); err != nil {
+				log.Printf("Error reloading config after fs event: %v", err)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Config watcher error: %v", err)
+		}
+	}
+}
+
+// reload re-reads and parses cm.path, diffs it against the currently-applied
+// config, unregisters metrics for targets that disappeared, and starts/stops
+// scrape goroutines to match. It never calls log.Fatalf: a bad edit to the
+// config file is reported via the returned error and dap_abi_config_reload_success
+// instead of killing the process.
+func (cm *configManager) reload() error {
+	next, err := readConfig(cm.path)
+	if err != nil {
+		configReloadSuccess.Set(0)
+		return err
+	}
+
+	cm.mu.Lock()
+	previous := cm.current
+	cm.current = next
+	cm.mu.Unlock()
+
+	for _, urlConfig := range removedURLConfigs(previous.URLs, next.URLs) {
+		unregisterTarget(urlConfig)
+	}
+
+	cm.scraper.reload(next)
+	configReloadSuccess.Set(1)
+	return nil
+}
+
+// reloadHandler implements `POST /-/reload`, mirroring the Prometheus/pint
+// convention: reparse and apply the config file immediately, returning 400
+// with the validation error instead of taking the process down.
+func (cm *configManager) reloadHandler(w http.ResponseWriter, r *http.Request) {
+	if err := cm.reload(); err != nil {
+		http.Error(w, fmt.Sprintf("Error reloading config: %v", err), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// urlConfigKey identifies a target's metric label set. It must stay in sync
+// with the labels passed to unregisterTarget and the WithLabelValues calls
+// in fetchData.
+type urlConfigKey struct {
+	url              string
+	originPrometheus string
+}
+
+func keyOf(urlConfig URLConfig) urlConfigKey {
+	return urlConfigKey{url: urlConfig.URL, originPrometheus: urlConfig.OriginPrometheus}
+}
+
+// removedURLConfigs returns the entries present in previous but absent from
+// next, keyed by the full (url, origin_prometheus) label pair so that
+// changing a target's OriginPrometheus is treated as removing the old
+// series rather than leaving it stale.
+func removedURLConfigs(previous, next []URLConfig) []URLConfig {
+	nextKeys := make(map[urlConfigKey]bool, len(next))
+	for _, urlConfig := range next {
+		nextKeys[keyOf(urlConfig)] = true
+	}
+
+	var removed []URLConfig
+	for _, urlConfig := range previous {
+		if !nextKeys[keyOf(urlConfig)] {
+			removed = append(removed, urlConfig)
+		}
+	}
+	return removed
+}
+
+// unregisterTarget deletes all metric series for a target that has been
+// dropped from the configuration.
+func unregisterTarget(urlConfig URLConfig) {
+	metric.DeleteLabelValues(urlConfig.URL, urlConfig.OriginPrometheus)
+	scrapeDuration.DeleteLabelValues(urlConfig.URL, urlConfig.OriginPrometheus)
+	scrapeSuccess.DeleteLabelValues(urlConfig.URL, urlConfig.OriginPrometheus)
+	scrapeErrors.DeleteLabelValues(urlConfig.URL, urlConfig.OriginPrometheus)
+	lastScrapeTimestamp.DeleteLabelValues(urlConfig.URL, urlConfig.OriginPrometheus)
+}
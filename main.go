@@ -1,37 +1,41 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"io/ioutil"
 	"log"
 	"math"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-type APIResponse struct {
-	Status  int         `json:"status"`
-	Message interface{} `json:"message"`
-	Data    struct {
-		AuthorizerDate string `json:"AuthorizerDate"`
-		DateLimit      string `json:"DateLimit"`
-	} `json:"data"`
-	Error interface{} `json:"error"`
-}
-
 type URLConfig struct {
-	URL              string `json:"url"`
-	Label            string `json:"label"`
-	OriginPrometheus string `json:"origin_prometheus"`
+	URL              string            `json:"url"`
+	Label            string            `json:"label"`
+	OriginPrometheus string            `json:"origin_prometheus"`
+	Timeout          string            `json:"timeout"`  // e.g. "10s", defaults to defaultScrapeTimeout
+	Interval         string            `json:"interval"` // e.g. "1h", defaults to defaultScrapeInterval
+	Retries          int               `json:"retries"`  // defaults to defaultScrapeRetries
+	Method           string            `json:"method"`   // defaults to defaultScrapeMethod
+	Headers          map[string]string `json:"headers"`
+	Body             string            `json:"body"`
+	Parser           string            `json:"parser"`      // "jsonpath" (default), "regex", or "x509"
+	DateLayout       string            `json:"date_layout"` // time.Parse reference layout, defaults to defaultDateLayout
+	DateField        string            `json:"date_field"`  // JSONPath or regex, meaning depends on Parser
 }
 
 type Config struct {
-	URLs []URLConfig `json:"urls"`
+	URLs    []URLConfig          `json:"urls"` // optional: the static scrape loop is skipped when empty
+	Metrics MetricsConfig        `json:"metrics"`
+	Modules map[string]URLConfig `json:"modules"` // named probe modules for GET /probe?target=&module=
 }
 
 var (
@@ -42,29 +46,44 @@ var (
 		},
 		[]string{"url", "origin_prometheus"}, // Adding origin_prometheus as a label
 	)
+	scrapeDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "dap_abi_scrape_duration_seconds",
+			Help:    "Duration of a scrape of a target (fetch and parse), in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"url", "origin_prometheus"},
+	)
+	scrapeSuccess = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dap_abi_scrape_success",
+			Help: "Whether the last scrape of a target succeeded (1) or failed (0)",
+		},
+		[]string{"url", "origin_prometheus"},
+	)
+	scrapeErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dap_abi_scrape_errors_total",
+			Help: "Total number of failed scrapes for a target",
+		},
+		[]string{"url", "origin_prometheus"},
+	)
+	lastScrapeTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "dap_abi_last_scrape_timestamp_seconds",
+			Help: "Unix timestamp of the last scrape attempt for a target",
+		},
+		[]string{"url", "origin_prometheus"},
+	)
+	configReloadSuccess = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "dap_abi_config_reload_success",
+			Help: "Whether the last configuration reload succeeded (1) or failed (0)",
+		},
+	)
 	registry = prometheus.NewRegistry()
 )
 
-func init() {
-	// Read configuration from file
-	configFile := flag.String("config", "config.json", "Path to the configuration file")
-	flag.Parse()
-
-	// Register the metric
-	registry.MustRegister(metric)
-
-	// Initialize metrics with default values
-	config, err := readConfig(*configFile)
-	if err != nil {
-		log.Fatalf("Error reading config file: %v", err)
-	}
-
-	for _, urlConfig := range config.URLs {
-		// Initialize metric values for each URL
-		metric.WithLabelValues(urlConfig.URL, urlConfig.OriginPrometheus).Set(-1) // Set default value to -1
-	}
-}
-
 func readConfig(filePath string) (Config, error) {
 	var config Config
 	data, err := ioutil.ReadFile(filePath)
@@ -75,49 +94,56 @@ func readConfig(filePath string) (Config, error) {
 	return config, err
 }
 
+// fetchData performs a single scrape of urlConfig using the parser selected
+// by urlConfig.Parser, retrying transient HTTP failures, and records both
+// the cert-expiry gauge and the auxiliary scrape-health metrics (duration,
+// success, errors, last-scrape timestamp).
 func fetchData(urlConfig URLConfig) {
-	// Create a POST request
-	req, err := http.NewRequest("POST", urlConfig.URL, nil)
-	if err != nil {
-		log.Printf("Error creating POST request for %s: %v", urlConfig.URL, err)
-		metric.WithLabelValues(urlConfig.URL, urlConfig.OriginPrometheus).Set(-1) // Set default value on failure
-		return
-	}
+	labels := []string{urlConfig.URL, urlConfig.OriginPrometheus}
+	start := time.Now()
 
-	// Perform the request
-	resp, err := http.DefaultClient.Do(req)
+	parser, err := parserFor(urlConfig)
 	if err != nil {
-		log.Printf("Error performing POST request to %s: %v", urlConfig.URL, err)
-		metric.WithLabelValues(urlConfig.URL, urlConfig.OriginPrometheus).Set(-1) // Set default value on failure
+		log.Printf("Error selecting parser for %s: %v", urlConfig.URL, err)
+		fail(labels)
 		return
 	}
-	defer resp.Body.Close()
 
-	var apiResponse APIResponse
-	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-		log.Printf("Error decoding JSON response from %s: %v", urlConfig.URL, err)
-		metric.WithLabelValues(urlConfig.URL, urlConfig.OriginPrometheus).Set(-1) // Set default value on failure
-		return
+	var resp *http.Response
+	if parser.NeedsHTTPFetch() {
+		client := &http.Client{Timeout: scrapeTimeout(urlConfig)}
+		resp, err = fetchWithRetry(client, urlConfig)
+		if err != nil {
+			log.Printf("Error performing %s request to %s: %v", scrapeMethod(urlConfig), urlConfig.URL, err)
+			lastScrapeTimestamp.WithLabelValues(labels...).Set(float64(time.Now().Unix()))
+			scrapeDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+			fail(labels)
+			return
+		}
+		defer resp.Body.Close()
 	}
 
-	// Check if DateLimit field is empty
-	if apiResponse.Data.DateLimit == "" {
-		log.Printf("DateLimit is empty for URL %s", urlConfig.URL)
-		metric.WithLabelValues(urlConfig.URL, urlConfig.OriginPrometheus).Set(-1) // Set default value on failure
-		return
-	}
+	dateLimit, err := parser.Parse(urlConfig, resp)
+	lastScrapeTimestamp.WithLabelValues(labels...).Set(float64(time.Now().Unix()))
+	scrapeDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
 
-	// Parse DateLimit field
-	dateLimit, err := time.Parse("2006-01-02 15:04:05", apiResponse.Data.DateLimit)
 	if err != nil {
-		log.Printf("Error parsing DateLimit from %s: %v", urlConfig.URL, err)
-		metric.WithLabelValues(urlConfig.URL, urlConfig.OriginPrometheus).Set(-1) // Set default value on failure
+		log.Printf("Error parsing expiry for %s: %v", urlConfig.URL, err)
+		fail(labels)
 		return
 	}
 
-	currentTime := time.Now()
-	dateDiff := dateLimit.Sub(currentTime).Hours() / 24
-	metric.WithLabelValues(urlConfig.URL, urlConfig.OriginPrometheus).Set(round(dateDiff, 2))
+	dateDiff := dateLimit.Sub(time.Now()).Hours() / 24
+	metric.WithLabelValues(labels...).Set(round(dateDiff, 2))
+	scrapeSuccess.WithLabelValues(labels...).Set(1)
+}
+
+// fail marks a scrape attempt as failed across the cert-expiry gauge and the
+// auxiliary scrape-health metrics for the given url/origin_prometheus labels.
+func fail(labels []string) {
+	metric.WithLabelValues(labels...).Set(-1)
+	scrapeSuccess.WithLabelValues(labels...).Set(0)
+	scrapeErrors.WithLabelValues(labels...).Inc()
 }
 
 // round function to round the value to the specified number of decimal places
@@ -127,26 +153,69 @@ func round(value float64, precision int) float64 {
 }
 
 func main() {
-	r := mux.NewRouter()
-	r.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	configFile := flag.String("config", "config.json", "Path to the configuration file")
+	flag.Parse()
+
+	registry.MustRegister(metric, scrapeDuration, scrapeSuccess, scrapeErrors, lastScrapeTimestamp, configReloadSuccess)
+
+	config, err := readConfig(*configFile)
+	if err != nil {
+		log.Fatalf("Error reading config file: %v", err)
+	}
+
+	for _, urlConfig := range config.URLs {
+		// Initialize each target's cert-expiry gauge before its first scrape.
+		metric.WithLabelValues(urlConfig.URL, urlConfig.OriginPrometheus).Set(-1)
+	}
+
+	// Fan out per-target scraping onto a worker pool; each target is
+	// scheduled independently according to its own Interval.
+	scraper := newScraperManager()
+	scraper.reload(config)
+	configReloadSuccess.Set(1)
+
+	cm := newConfigManager(*configFile, config, scraper)
+	go cm.watch()
+
+	appRouter := mux.NewRouter()
+	appRouter.HandleFunc("/-/reload", cm.reloadHandler).Methods(http.MethodPost)
+	appRouter.HandleFunc("/healthz", healthzHandler)
+	appRouter.HandleFunc("/readyz", readyzHandler(scraper))
+
+	// /probe lives on metricsRouter, not appRouter: see the doc comment on
+	// probeHandler for why.
+	metricsRouter := mux.NewRouter()
+	metricsRouter.Handle(config.Metrics.path(), metricsHandler(config.Metrics))
+	metricsRouter.Handle("/probe", requireMetricsAuth(config.Metrics, probeHandler(cm))).Methods(http.MethodGet)
+
+	appServer := &http.Server{Addr: appListenAddr, Handler: appRouter}
+	metricsServer := &http.Server{Addr: config.Metrics.addr(), Handler: metricsRouter}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Fetch data periodically
 	go func() {
-		for {
-			// Read configuration from file and update metrics
-			configFile := "config.json"
-			config, err := readConfig(configFile)
-			if err != nil {
-				log.Fatalf("Error reading config file: %v", err)
-			}
-
-			for _, urlConfig := range config.URLs {
-				fetchData(urlConfig)
-			}
-			time.Sleep(10 * time.Hour)
+		log.Printf("Starting app server on %s", appServer.Addr)
+		if err := appServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("App server error: %v", err)
+		}
+	}()
+
+	go func() {
+		log.Printf("Starting metrics server on %s%s", metricsServer.Addr, config.Metrics.path())
+		var err error
+		if config.Metrics.TLSCertFile != "" {
+			err = metricsServer.ListenAndServeTLS(config.Metrics.TLSCertFile, config.Metrics.TLSKeyFile)
+		} else {
+			err = metricsServer.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Metrics server error: %v", err)
 		}
 	}()
 
-	log.Println("Starting server on :18000")
-	log.Fatal(http.ListenAndServe(":18000", r))
+	<-ctx.Done()
+	log.Println("Shutting down...")
+	shutdownServer(context.Background(), "app", appServer)
+	shutdownServer(context.Background(), "metrics", metricsServer)
 }
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PaesslerAG/jsonpath"
+)
+
+const (
+	parserJSONPath = "jsonpath"
+	parserRegex    = "regex"
+	parserX509     = "x509"
+
+	defaultDateLayout = "2006-01-02 15:04:05"
+	defaultDateField  = "$.data.DateLimit"
+)
+
+// ExpiryParser extracts a certificate/credential expiry time from a scrape.
+// Implementations are selected per-target via URLConfig.Parser.
+type ExpiryParser interface {
+	// Parse returns the expiry time for urlConfig. resp is the response
+	// returned by fetchWithRetry, or nil when NeedsHTTPFetch is false.
+	Parse(urlConfig URLConfig, resp *http.Response) (time.Time, error)
+
+	// NeedsHTTPFetch reports whether fetchData must perform an HTTP request
+	// before calling Parse. The x509 parser dials the target itself and
+	// does not need one.
+	NeedsHTTPFetch() bool
+}
+
+// parserFor resolves the ExpiryParser named by urlConfig.Parser, defaulting
+// to jsonpath to preserve the original APIResponse-shaped behaviour.
+func parserFor(urlConfig URLConfig) (ExpiryParser, error) {
+	switch urlConfig.Parser {
+	case "", parserJSONPath:
+		return jsonPathParser{}, nil
+	case parserRegex:
+		return regexParser{}, nil
+	case parserX509:
+		return x509Parser{}, nil
+	default:
+		return nil, fmt.Errorf("unknown parser %q", urlConfig.Parser)
+	}
+}
+
+func dateLayout(urlConfig URLConfig) string {
+	if urlConfig.DateLayout == "" {
+		return defaultDateLayout
+	}
+	return urlConfig.DateLayout
+}
+
+func dateField(urlConfig URLConfig) string {
+	if urlConfig.DateField == "" {
+		return defaultDateField
+	}
+	return urlConfig.DateField
+}
+
+// jsonPathParser decodes the response body as JSON and extracts the expiry
+// timestamp from the field named by URLConfig.DateField, a JSONPath
+// expression that defaults to the original `data.DateLimit` shape.
+type jsonPathParser struct{}
+
+func (jsonPathParser) NeedsHTTPFetch() bool { return true }
+
+func (jsonPathParser) Parse(urlConfig URLConfig, resp *http.Response) (time.Time, error) {
+	var doc interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return time.Time{}, fmt.Errorf("decoding JSON response: %w", err)
+	}
+
+	value, err := jsonpath.Get(dateField(urlConfig), doc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("evaluating %s: %w", dateField(urlConfig), err)
+	}
+
+	raw, ok := value.(string)
+	if !ok || raw == "" {
+		return time.Time{}, fmt.Errorf("%s is empty or not a string", dateField(urlConfig))
+	}
+
+	return time.Parse(dateLayout(urlConfig), raw)
+}
+
+// regexParser reads the raw response body and extracts the expiry timestamp
+// from the first capture group of URLConfig.DateField, treated as a regular
+// expression rather than a JSONPath.
+type regexParser struct{}
+
+func (regexParser) NeedsHTTPFetch() bool { return true }
+
+func (regexParser) Parse(urlConfig URLConfig, resp *http.Response) (time.Time, error) {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("reading response body: %w", err)
+	}
+
+	re, err := regexp.Compile(dateField(urlConfig))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("compiling date_field regex: %w", err)
+	}
+
+	matches := re.FindSubmatch(body)
+	if len(matches) < 2 {
+		return time.Time{}, fmt.Errorf("regex %s found no match in response body", dateField(urlConfig))
+	}
+
+	return time.Parse(dateLayout(urlConfig), string(matches[1]))
+}
+
+// x509Parser dials urlConfig.URL (an "https://host:port" address) directly
+// and returns the NotAfter of the leaf certificate presented during the TLS
+// handshake, for monitoring bare TLS endpoints that expose no JSON API.
+type x509Parser struct{}
+
+func (x509Parser) NeedsHTTPFetch() bool { return false }
+
+func (x509Parser) Parse(urlConfig URLConfig, _ *http.Response) (time.Time, error) {
+	hostPort := strings.TrimPrefix(urlConfig.URL, "https://")
+
+	dialer := &net.Dialer{Timeout: scrapeTimeout(urlConfig)}
+	// InsecureSkipVerify: we only want the presented certificate's NotAfter,
+	// even if the chain is already expired or otherwise untrusted.
+	conn, err := tls.DialWithDialer(dialer, "tcp", hostPort, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("dialing %s: %w", hostPort, err)
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return time.Time{}, fmt.Errorf("no peer certificates presented by %s", hostPort)
+	}
+
+	return certs[0].NotAfter, nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func respWithBody(body string) *http.Response {
+	return &http.Response{Body: io.NopCloser(strings.NewReader(body))}
+}
+
+func TestJSONPathParser(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		dateField string
+		wantErr   bool
+	}{
+		{
+			name: "default field matches original APIResponse shape",
+			body: `{"status":200,"data":{"DateLimit":"2030-01-02 15:04:05"}}`,
+		},
+		{
+			name:      "custom date_field",
+			body:      `{"expiry":{"date":"2030-01-02 15:04:05"}}`,
+			dateField: "$.expiry.date",
+		},
+		{
+			name:    "field missing",
+			body:    `{"data":{}}`,
+			wantErr: true,
+		},
+		{
+			name:    "not valid JSON",
+			body:    `not json`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlConfig := URLConfig{DateField: tc.dateField}
+			got, err := (jsonPathParser{}).Parse(urlConfig, respWithBody(tc.body))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want, _ := time.Parse(defaultDateLayout, "2030-01-02 15:04:05")
+			if !got.Equal(want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRegexParser(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		dateField string
+		wantErr   bool
+	}{
+		{
+			name:      "capture group matches",
+			body:      `cert expires: 2030-01-02 15:04:05 UTC`,
+			dateField: `expires: (\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})`,
+		},
+		{
+			name:      "no match",
+			body:      `nothing useful here`,
+			dateField: `expires: (\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})`,
+			wantErr:   true,
+		},
+		{
+			name:      "invalid regex",
+			body:      `irrelevant`,
+			dateField: `(unterminated`,
+			wantErr:   true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlConfig := URLConfig{DateField: tc.dateField}
+			got, err := (regexParser{}).Parse(urlConfig, respWithBody(tc.body))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want, _ := time.Parse(defaultDateLayout, "2030-01-02 15:04:05")
+			if !got.Equal(want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestParserFor(t *testing.T) {
+	cases := []struct {
+		parser  string
+		want    interface{}
+		wantErr bool
+	}{
+		{parser: "", want: jsonPathParser{}},
+		{parser: parserJSONPath, want: jsonPathParser{}},
+		{parser: parserRegex, want: regexParser{}},
+		{parser: parserX509, want: x509Parser{}},
+		{parser: "unknown", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.parser, func(t *testing.T) {
+			got, err := parserFor(URLConfig{Parser: tc.parser})
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("got %T, want %T", got, tc.want)
+			}
+		})
+	}
+}
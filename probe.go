@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// probeHandler implements `GET /probe?target=<url>&module=<name>` in the
+// style of blackbox_exporter: each scrape performs a single on-demand fetch
+// against target using the named module's method/headers/body/parser/timeout,
+// and returns a fresh registry. This decouples scrape cadence from the
+// exporter's internal loop and lets Prometheus's own relabel_configs drive
+// the target list instead of enumerating every URL in config.json.
+//
+// It is registered on metricsRouter, not appRouter: it's scraped on every
+// Prometheus poll like /metrics, and modules using the x509 parser make the
+// process dial an arbitrary caller-supplied target, so it must sit behind
+// the same TLS/basic-auth gate as the rest of the scrape surface.
+func probeHandler(cm *configManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		target := r.URL.Query().Get("target")
+		if target == "" {
+			http.Error(w, "target parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		moduleName := r.URL.Query().Get("module")
+		module, ok := cm.snapshot().Modules[moduleName]
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown module %q", moduleName), http.StatusBadRequest)
+			return
+		}
+
+		urlConfig := module
+		urlConfig.URL = target
+
+		registry := prometheus.NewRegistry()
+		metrics := newProbeMetrics()
+		registry.MustRegister(metrics.success, metrics.duration, metrics.httpStatusCode, metrics.certExpiry)
+
+		runProbe(urlConfig, metrics)
+
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	}
+}
+
+// probeMetrics holds the per-request registry populated by runProbe.
+type probeMetrics struct {
+	success        prometheus.Gauge
+	duration       prometheus.Gauge
+	httpStatusCode prometheus.Gauge
+	certExpiry     prometheus.Gauge
+}
+
+func newProbeMetrics() *probeMetrics {
+	return &probeMetrics{
+		success: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "probe_success",
+			Help: "Whether the probe of the target succeeded",
+		}),
+		duration: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "probe_duration_seconds",
+			Help: "Duration of the probe in seconds",
+		}),
+		httpStatusCode: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "probe_http_status_code",
+			Help: "HTTP status code returned by the probed target, 0 if the request failed or the parser bypasses HTTP",
+		}),
+		certExpiry: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "dap_abi_cert_expired_day",
+			Help: "Difference in days between DateLimit and current date",
+		}),
+	}
+}
+
+// runProbe performs a single on-demand fetch against urlConfig, recording
+// the outcome on metrics. It never calls fail() or touches the exporter's
+// own registry; probe results live entirely in the per-request registry.
+func runProbe(urlConfig URLConfig, metrics *probeMetrics) {
+	start := time.Now()
+	defer func() { metrics.duration.Set(time.Since(start).Seconds()) }()
+
+	parser, err := parserFor(urlConfig)
+	if err != nil {
+		log.Printf("Error selecting parser for probe of %s: %v", urlConfig.URL, err)
+		return
+	}
+
+	var resp *http.Response
+	if parser.NeedsHTTPFetch() {
+		client := &http.Client{Timeout: scrapeTimeout(urlConfig)}
+		resp, err = fetchWithRetry(client, urlConfig)
+		if err != nil {
+			log.Printf("Error probing %s: %v", urlConfig.URL, err)
+			return
+		}
+		defer resp.Body.Close()
+		metrics.httpStatusCode.Set(float64(resp.StatusCode))
+	}
+
+	dateLimit, err := parser.Parse(urlConfig, resp)
+	if err != nil {
+		log.Printf("Error parsing expiry while probing %s: %v", urlConfig.URL, err)
+		return
+	}
+
+	metrics.certExpiry.Set(round(dateLimit.Sub(time.Now()).Hours()/24, 2))
+	metrics.success.Set(1)
+}
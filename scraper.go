@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultScrapeInterval = 10 * time.Hour
+	defaultScrapeTimeout  = 30 * time.Second
+	defaultScrapeRetries  = 3
+	defaultScrapeMethod   = http.MethodPost
+	defaultWorkerPoolSize = 8
+)
+
+// retryBaseBackoff is the delay before the first retry in fetchWithRetry,
+// doubling on each subsequent attempt. It's a var rather than a const so
+// tests can shrink it and exercise the retry/backoff counting without
+// actually sleeping.
+var retryBaseBackoff = 500 * time.Millisecond
+
+// scrapeJob is a single fetch enqueued onto the worker pool.
+type scrapeJob struct {
+	urlConfig URLConfig
+}
+
+// scraperManager owns the worker pool and the set of per-target scheduling
+// goroutines, and lets a config reload start/stop targets without
+// restarting the process. It also tracks whether the current generation of
+// targets has completed its first scrape, for readyzHandler.
+//
+// Both cancels and remaining are keyed on the full (url, origin_prometheus)
+// pair, not URL alone: the same URL can legitimately appear twice with a
+// different origin_prometheus, and keying on URL would let one of the two
+// scheduleTarget goroutines (and its readiness bookkeeping) get clobbered by
+// the other, same as the metrics diff in config.go had to account for.
+type scraperManager struct {
+	mu      sync.Mutex
+	jobs    chan scrapeJob
+	cancels map[urlConfigKey]context.CancelFunc
+
+	readyMu   sync.Mutex
+	remaining map[urlConfigKey]bool
+	ready     bool
+}
+
+// newScraperManager starts a fixed-size worker pool and returns a manager
+// with no targets scheduled yet; call reload to apply a Config.
+func newScraperManager() *scraperManager {
+	m := &scraperManager{
+		jobs:    make(chan scrapeJob),
+		cancels: make(map[urlConfigKey]context.CancelFunc),
+	}
+	for i := 0; i < defaultWorkerPoolSize; i++ {
+		go m.scrapeWorker()
+	}
+	return m
+}
+
+// reload stops every currently-scheduled target and starts fresh scheduling
+// goroutines for config.URLs, so each target fetches concurrently and on its
+// own cadence instead of sharing a single interval. Readiness resets: the
+// manager is not ready again until every target in config has scraped once.
+func (m *scraperManager) reload(config Config) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, cancel := range m.cancels {
+		cancel()
+		delete(m.cancels, key)
+	}
+
+	m.readyMu.Lock()
+	m.remaining = make(map[urlConfigKey]bool, len(config.URLs))
+	for _, urlConfig := range config.URLs {
+		m.remaining[keyOf(urlConfig)] = true
+	}
+	m.ready = len(config.URLs) == 0
+	m.readyMu.Unlock()
+
+	for _, urlConfig := range config.URLs {
+		ctx, cancel := context.WithCancel(context.Background())
+		m.cancels[keyOf(urlConfig)] = cancel
+		go scheduleTarget(ctx, urlConfig, m.jobs)
+	}
+}
+
+// isReady reports whether every target from the last reload has completed
+// at least one scrape attempt.
+func (m *scraperManager) isReady() bool {
+	m.readyMu.Lock()
+	defer m.readyMu.Unlock()
+	return m.ready
+}
+
+func (m *scraperManager) markScraped(key urlConfigKey) {
+	m.readyMu.Lock()
+	defer m.readyMu.Unlock()
+	delete(m.remaining, key)
+	if len(m.remaining) == 0 {
+		m.ready = true
+	}
+}
+
+func scheduleTarget(ctx context.Context, urlConfig URLConfig, jobs chan<- scrapeJob) {
+	ticker := time.NewTicker(scrapeInterval(urlConfig))
+	defer ticker.Stop()
+
+	select {
+	case <-ctx.Done():
+		return
+	case jobs <- scrapeJob{urlConfig: urlConfig}:
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- scrapeJob{urlConfig: urlConfig}:
+			}
+		}
+	}
+}
+
+func (m *scraperManager) scrapeWorker() {
+	for job := range m.jobs {
+		fetchData(job.urlConfig)
+		m.markScraped(keyOf(job.urlConfig))
+	}
+}
+
+func scrapeInterval(urlConfig URLConfig) time.Duration {
+	if urlConfig.Interval == "" {
+		return defaultScrapeInterval
+	}
+	d, err := time.ParseDuration(urlConfig.Interval)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid interval %q for %s, using default %s", urlConfig.Interval, urlConfig.URL, defaultScrapeInterval)
+		return defaultScrapeInterval
+	}
+	return d
+}
+
+func scrapeTimeout(urlConfig URLConfig) time.Duration {
+	if urlConfig.Timeout == "" {
+		return defaultScrapeTimeout
+	}
+	d, err := time.ParseDuration(urlConfig.Timeout)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid timeout %q for %s, using default %s", urlConfig.Timeout, urlConfig.URL, defaultScrapeTimeout)
+		return defaultScrapeTimeout
+	}
+	return d
+}
+
+func scrapeRetries(urlConfig URLConfig) int {
+	if urlConfig.Retries <= 0 {
+		return defaultScrapeRetries
+	}
+	return urlConfig.Retries
+}
+
+func scrapeMethod(urlConfig URLConfig) string {
+	if urlConfig.Method == "" {
+		return defaultScrapeMethod
+	}
+	return strings.ToUpper(urlConfig.Method)
+}
+
+// fetchWithRetry performs the HTTP round trip described by urlConfig,
+// retrying network errors and 5xx responses with exponential backoff up to
+// urlConfig.Retries times.
+func fetchWithRetry(client *http.Client, urlConfig URLConfig) (*http.Response, error) {
+	retries := scrapeRetries(urlConfig)
+	backoff := retryBaseBackoff
+
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		var body io.Reader
+		if urlConfig.Body != "" {
+			body = strings.NewReader(urlConfig.Body)
+		}
+
+		req, err := http.NewRequest(scrapeMethod(urlConfig), urlConfig.URL, body)
+		if err != nil {
+			return nil, fmt.Errorf("creating %s request for %s: %w", scrapeMethod(urlConfig), urlConfig.URL, err)
+		}
+		for k, v := range urlConfig.Headers {
+			req.Header.Set(k, v)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode >= 500 {
+			resp.Body.Close()
+			lastErr = fmt.Errorf("server error: %s", resp.Status)
+			continue
+		}
+		return resp, nil
+	}
+	return nil, lastErr
+}
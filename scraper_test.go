@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// withFastRetries shrinks retryBaseBackoff for the duration of a test so
+// retry/backoff counting can be exercised without actually sleeping.
+func withFastRetries(t *testing.T) {
+	t.Helper()
+	original := retryBaseBackoff
+	retryBaseBackoff = time.Millisecond
+	t.Cleanup(func() { retryBaseBackoff = original })
+}
+
+func TestFetchWithRetrySucceedsAfterTransientFailures(t *testing.T) {
+	withFastRetries(t)
+
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) <= 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	urlConfig := URLConfig{URL: server.URL, Retries: 3}
+	resp, err := fetchWithRetry(server.Client(), urlConfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("got status %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Errorf("got %d attempts, want 3 (1 initial + 2 retries)", got)
+	}
+}
+
+func TestFetchWithRetryExhaustsRetries(t *testing.T) {
+	withFastRetries(t)
+
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	urlConfig := URLConfig{URL: server.URL, Retries: 2}
+	_, err := fetchWithRetry(server.Client(), urlConfig)
+	if err == nil {
+		t.Fatal("expected an error after exhausting retries, got none")
+	}
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Errorf("got %d attempts, want 3 (1 initial + 2 retries)", got)
+	}
+}
+
+func TestFetchWithRetryNoRetryOnSuccess(t *testing.T) {
+	withFastRetries(t)
+
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	urlConfig := URLConfig{URL: server.URL, Retries: 5}
+	resp, err := fetchWithRetry(server.Client(), urlConfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if got := atomic.LoadInt32(&attempts); got != 1 {
+		t.Errorf("got %d attempts, want 1 (no retries needed)", got)
+	}
+}
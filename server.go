@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsConfig configures the dedicated Prometheus scrape listener,
+// independent of the application router, analogous to the PROMETHEUS_CONFIG
+// block used by other exporters.
+type MetricsConfig struct {
+	Host              string `json:"host"`
+	Port              int    `json:"port"`
+	Path              string `json:"path"`
+	TLSCertFile       string `json:"tls_cert_file"`
+	TLSKeyFile        string `json:"tls_key_file"`
+	BasicAuthUsername string `json:"basic_auth_username"`
+	BasicAuthPassword string `json:"basic_auth_password"`
+}
+
+const (
+	defaultMetricsPort = 9273
+	defaultMetricsPath = "/metrics"
+	appListenAddr      = ":18000"
+	shutdownTimeout    = 10 * time.Second
+)
+
+func (mc MetricsConfig) addr() string {
+	port := mc.Port
+	if port == 0 {
+		port = defaultMetricsPort
+	}
+	return mc.Host + ":" + strconv.Itoa(port)
+}
+
+func (mc MetricsConfig) path() string {
+	if mc.Path == "" {
+		return defaultMetricsPath
+	}
+	return mc.Path
+}
+
+// metricsHandler serves the Prometheus registry, gated by HTTP basic auth
+// when MetricsConfig.BasicAuthUsername is set.
+func metricsHandler(mc MetricsConfig) http.Handler {
+	return requireMetricsAuth(mc, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+}
+
+// requireMetricsAuth wraps next with the same HTTP basic auth gate as
+// metricsHandler, for other scrape-surface endpoints (e.g. /probe) that must
+// live behind MetricsConfig's TLS/auth rather than the plaintext management
+// listener.
+func requireMetricsAuth(mc MetricsConfig, next http.Handler) http.Handler {
+	if mc.BasicAuthUsername == "" {
+		return next
+	}
+	return basicAuth(next, mc.BasicAuthUsername, mc.BasicAuthPassword)
+}
+
+func basicAuth(next http.Handler, username, password string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || subtle.ConstantTimeCompare([]byte(user), []byte(username)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(pass), []byte(password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="metrics"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// healthzHandler is a liveness check: it reports ok as soon as the process
+// is serving traffic, regardless of scrape state.
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// readyzHandler is a readiness check: it reports ok once every
+// currently-configured target has completed at least one scrape attempt
+// since the last config reload.
+func readyzHandler(scraper *scraperManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !scraper.isReady() {
+			http.Error(w, "scrape cycle not yet complete", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// shutdownServer gracefully stops srv within shutdownTimeout, logging rather
+// than failing on a timeout.
+func shutdownServer(ctx context.Context, name string, srv *http.Server) {
+	shutdownCtx, cancel := context.WithTimeout(ctx, shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down %s server: %v", name, err)
+	}
+}